@@ -0,0 +1,125 @@
+// Package api implements the configs service's HTTP API: storing and
+// retrieving per-organisation rule and alertmanager configuration.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/weaveworks/common/user"
+
+	"github.com/weaveworks/cortex/configs"
+	"github.com/weaveworks/cortex/configs/db"
+)
+
+// API implements the configs service HTTP API.
+type API struct {
+	db db.DB
+	http.Handler
+}
+
+// New creates a new API.
+func New(database db.DB) *API {
+	a := &API{db: database}
+	r := mux.NewRouter()
+	a.RegisterRoutes(r)
+	a.Handler = r
+	return a
+}
+
+// RegisterRoutes registers the configs API's routes with the given router.
+// The /api/configs/org/... routes require an authenticated org (read from
+// the X-Scope-OrgID header by authenticated); the /private/api/... routes are
+// only reachable from inside the cluster and operate across all orgs.
+func (a *API) RegisterRoutes(r *mux.Router) {
+	for _, route := range []struct {
+		name, method, path string
+		handler            http.HandlerFunc
+	}{
+		{"root", "GET", "/", a.admin},
+		{"get_config", "GET", "/api/configs/org/{subsystem}", authenticated(a.getConfig)},
+		{"set_config", "POST", "/api/configs/org/{subsystem}", authenticated(a.setConfig)},
+		{"get_configs", "GET", "/private/api/configs/org/{subsystem}", a.getConfigs},
+		{"watch_configs", "GET", "/private/api/configs/org/{subsystem}/watch", a.watchConfigs},
+	} {
+		r.Handle(route.path, route.handler).Methods(route.method).Name(route.name)
+	}
+}
+
+func authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID := r.Header.Get(user.OrgIDHeaderName)
+		if orgID == "" {
+			http.Error(w, "no org id", http.StatusUnauthorized)
+			return
+		}
+		ctx := user.InjectOrgID(r.Context(), orgID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func (a *API) admin(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *API) getConfig(w http.ResponseWriter, r *http.Request) {
+	orgID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	view, err := a.db.GetConfig(r.Context(), configs.OrgID(orgID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(view)
+}
+
+func (a *API) setConfig(w http.ResponseWriter, r *http.Request) {
+	orgID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var cfg configs.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.db.SetConfig(r.Context(), configs.OrgID(orgID), cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ConfigsView renders multiple configurations, mapping org ID to config
+// view, as returned by the private list/diff endpoints.
+type ConfigsView struct {
+	Configs map[configs.OrgID]configs.ConfigView `json:"configs"`
+}
+
+func (a *API) getConfigs(w http.ResponseWriter, r *http.Request) {
+	var (
+		cfgs map[configs.OrgID]configs.ConfigView
+		err  error
+	)
+	if sinceRaw := r.FormValue("since"); sinceRaw != "" {
+		since, parseErr := strconv.Atoi(sinceRaw)
+		if parseErr != nil {
+			http.Error(w, parseErr.Error(), http.StatusBadRequest)
+			return
+		}
+		cfgs, err = a.db.GetConfigs(r.Context(), configs.ID(since))
+	} else {
+		cfgs, err = a.db.GetAllConfigs(r.Context())
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(ConfigsView{Configs: cfgs})
+}
@@ -0,0 +1,88 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/weaveworks/cortex/configs"
+	"github.com/weaveworks/cortex/configs/api"
+	"github.com/weaveworks/cortex/configs/db"
+	"github.com/weaveworks/cortex/configs/db/memory"
+)
+
+var (
+	app      http.Handler
+	database db.DB
+
+	orgIDCounter int
+)
+
+func setup(t *testing.T) {
+	database = memory.New()
+	app = api.New(database)
+}
+
+func cleanup(t *testing.T) {
+	require.NoError(t, database.Close())
+}
+
+func request(t *testing.T, method, urlStr string, body io.Reader) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, urlStr, body)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func requestAsOrg(t *testing.T, orgID configs.OrgID, method, urlStr string, body io.Reader) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, urlStr, body)
+	require.NoError(t, err)
+	req.Header.Set(user.OrgIDHeaderName, string(orgID))
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	return w
+}
+
+func makeOrgID() configs.OrgID {
+	orgIDCounter++
+	return configs.OrgID(fmt.Sprintf("org%d", orgIDCounter))
+}
+
+func makeConfig() configs.Config {
+	return configs.Config{
+		RulesFiles: map[string]string{
+			"recording.rules": "ALERT Foo\n  IF up == 0\n",
+		},
+		RuleFormatVersion:  configs.RuleFormatV2,
+		AlertmanagerConfig: "route:\n  receiver: noop\n",
+	}
+}
+
+type jsonBody struct {
+	value interface{}
+}
+
+func jsonObject(v interface{}) jsonBody {
+	return jsonBody{value: v}
+}
+
+func (j jsonBody) Reader(t *testing.T) io.Reader {
+	b, err := json.Marshal(j.value)
+	require.NoError(t, err)
+	return bytes.NewReader(b)
+}
+
+func parseConfigView(t *testing.T, b []byte) configs.ConfigView {
+	var result configs.ConfigView
+	err := json.Unmarshal(b, &result)
+	require.NoError(t, err, "Could not unmarshal JSON")
+	return result
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/weaveworks/cortex/configs"
+)
+
+// watchConfigs is a long-lived streaming alternative to getConfigs: rather
+// than requiring ruler/alertmanager pollers to repeat GET ?since=<id>
+// requests on a timer, it keeps the connection open and emits a ConfigView
+// event, as Server-Sent Events, every time a new revision is persisted for
+// any org. Clients resume after a disconnect by sending back the last event
+// ID they saw as the Last-Event-ID header.
+func (a *API) watchConfigs(w http.ResponseWriter, r *http.Request) {
+	since, err := watchSinceID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+	updates, err := a.db.Subscribe(ctx, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			if err := writeConfigUpdateEvent(w, update); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// watchSinceID reads the starting config ID for a watch request, preferring
+// the Last-Event-ID header (sent automatically by EventSource on reconnect)
+// over the since query parameter used by the one-shot polling endpoint.
+func watchSinceID(r *http.Request) (configs.ID, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.FormValue("since")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	since, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid since/Last-Event-ID value: %q", raw)
+	}
+	return configs.ID(since), nil
+}
+
+func writeConfigUpdateEvent(w http.ResponseWriter, update configs.ConfigUpdate) error {
+	body, err := json.Marshal(struct {
+		OrgID configs.OrgID `json:"org_id"`
+		configs.ConfigView
+	}{OrgID: update.OrgID, ConfigView: update.ConfigView})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: config\ndata: %s\n\n", update.ID, body)
+	return err
+}
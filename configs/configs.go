@@ -0,0 +1,52 @@
+package configs
+
+// ID is the ID of a configuration, as stored in the database.
+type ID int
+
+// RuleFormatVersion indicates which Prometheus rule format (v1 or v2) to
+// use when parsing a Config's RulesFiles.
+type RuleFormatVersion int
+
+// Valid rule format versions.
+const (
+	RuleFormatV1 RuleFormatVersion = iota
+	RuleFormatV2
+)
+
+// IsValid returns whether the rules format version is a known version.
+func (v RuleFormatVersion) IsValid() bool {
+	switch v {
+	case RuleFormatV1, RuleFormatV2:
+		return true
+	default:
+		return false
+	}
+}
+
+// OrgID is the ID of an organisation.
+type OrgID string
+
+// Config is a Cortex configuration for a single organisation.
+type Config struct {
+	// RulesFiles maps from a rules filename to file contents.
+	RulesFiles         map[string]string `json:"rules_files"`
+	RuleFormatVersion  RuleFormatVersion `json:"rule_format_version"`
+	AlertmanagerConfig string            `json:"alertmanager_config"`
+}
+
+// ConfigView is what's returned when we ask for an organisation's
+// configuration. The ID is included so that clients can do concurrency
+// control and incremental polling/streaming on top of it.
+type ConfigView struct {
+	ID     ID     `json:"id"`
+	Config Config `json:"config"`
+}
+
+// ConfigUpdate is a single notification of a persisted configuration change,
+// as delivered by DB.Subscribe. It carries enough information for a
+// subscriber to apply the update without an additional round-trip to the
+// database.
+type ConfigUpdate struct {
+	OrgID OrgID
+	ConfigView
+}
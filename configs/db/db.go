@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"github.com/weaveworks/cortex/configs"
+	"github.com/weaveworks/cortex/configs/db/memory"
+	"github.com/weaveworks/cortex/configs/db/postgres"
+)
+
+// Config configures the config database.
+type Config struct {
+	URI string
+}
+
+// RegisterFlags adds the flags required to config this to the given FlagSet.
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.URI, "configs.database.uri", "memory://", "URI where the database can be found (for dev you can use memory://)")
+}
+
+// DB is the interface for the config storage service.
+//
+// Implementations must be safe for concurrent use by multiple goroutines,
+// and Subscribe must be safe to call concurrently with SetConfig.
+type DB interface {
+	// GetConfig gets the organisation's latest config.
+	GetConfig(ctx context.Context, orgID configs.OrgID) (configs.ConfigView, error)
+
+	// SetConfig sets the organisation's config.
+	SetConfig(ctx context.Context, orgID configs.OrgID, cfg configs.Config) error
+
+	// GetAllConfigs gets all of the organisations' configs.
+	GetAllConfigs(ctx context.Context) (map[configs.OrgID]configs.ConfigView, error)
+
+	// GetConfigs gets all of the configs that have changed since the given
+	// config ID.
+	GetConfigs(ctx context.Context, since configs.ID) (map[configs.OrgID]configs.ConfigView, error)
+
+	// Subscribe returns a channel that first carries the latest config of
+	// every org changed after sinceID (intermediate revisions are not
+	// replayed), followed by any further updates persisted while the caller
+	// is listening. A nil channel and a non-nil error are returned if the
+	// subscription cannot be established (e.g. the backlog query fails).
+	// Implementations must stop sending on the channel and release any
+	// resources once ctx is done.
+	Subscribe(ctx context.Context, sinceID configs.ID) (<-chan configs.ConfigUpdate, error)
+
+	Close() error
+}
+
+// New creates a new DB from the given config.
+func New(cfg Config) (DB, error) {
+	u, err := url.Parse(cfg.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URI: %v", err)
+	}
+	switch u.Scheme {
+	case "memory":
+		return memory.New(), nil
+	case "postgres":
+		return postgres.New(cfg.URI)
+	default:
+		return nil, fmt.Errorf("unknown database type: %s", u.Scheme)
+	}
+}
@@ -0,0 +1,6 @@
+package memory
+
+import "errors"
+
+// ErrNotFound is returned by GetConfig when the organisation has no config.
+var ErrNotFound = errors.New("config not found")
@@ -0,0 +1,129 @@
+// Package memory provides an in-memory implementation of configs/db.DB,
+// intended for tests and single-process development setups.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/weaveworks/cortex/configs"
+)
+
+// DB is an in-memory implementation of db.DB.
+type DB struct {
+	mu     sync.Mutex
+	cfgs   map[configs.OrgID]configs.ConfigView
+	nextID configs.ID
+	subs   map[*configs.Subscriber]struct{}
+}
+
+// New creates a new in-memory database.
+func New() *DB {
+	return &DB{
+		cfgs: map[configs.OrgID]configs.ConfigView{},
+		subs: map[*configs.Subscriber]struct{}{},
+	}
+}
+
+// GetConfig implements db.DB.
+func (d *DB) GetConfig(_ context.Context, orgID configs.OrgID) (configs.ConfigView, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	view, ok := d.cfgs[orgID]
+	if !ok {
+		return configs.ConfigView{}, ErrNotFound
+	}
+	return view, nil
+}
+
+// SetConfig implements db.DB.
+func (d *DB) SetConfig(_ context.Context, orgID configs.OrgID, cfg configs.Config) error {
+	d.mu.Lock()
+	d.nextID++
+	view := configs.ConfigView{ID: d.nextID, Config: cfg}
+	d.cfgs[orgID] = view
+
+	// Copy the subscriber set out so we can deliver to it after releasing
+	// d.mu: Subscriber.Deliver is non-blocking, but we still don't want to
+	// hold the map lock while iterating a (possibly large) set of them.
+	subs := make([]*configs.Subscriber, 0, len(d.subs))
+	for sub := range d.subs {
+		subs = append(subs, sub)
+	}
+	d.mu.Unlock()
+
+	update := configs.ConfigUpdate{OrgID: orgID, ConfigView: view}
+	for _, sub := range subs {
+		sub.Deliver(update)
+	}
+	return nil
+}
+
+// GetAllConfigs implements db.DB.
+func (d *DB) GetAllConfigs(_ context.Context) (map[configs.OrgID]configs.ConfigView, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := make(map[configs.OrgID]configs.ConfigView, len(d.cfgs))
+	for orgID, view := range d.cfgs {
+		result[orgID] = view
+	}
+	return result, nil
+}
+
+// GetConfigs implements db.DB.
+func (d *DB) GetConfigs(_ context.Context, since configs.ID) (map[configs.OrgID]configs.ConfigView, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := map[configs.OrgID]configs.ConfigView{}
+	for orgID, view := range d.cfgs {
+		if view.ID > since {
+			result[orgID] = view
+		}
+	}
+	return result, nil
+}
+
+// Subscribe implements db.DB. It replays the latest config of each org
+// changed since sinceID and then forwards subsequent updates as they're
+// persisted. A subscriber that falls
+// behind (its buffer fills because the caller isn't reading fast enough) is
+// disconnected rather than allowed to block SetConfig.
+func (d *DB) Subscribe(ctx context.Context, sinceID configs.ID) (<-chan configs.ConfigUpdate, error) {
+	d.mu.Lock()
+
+	// Buffer generously: the backlog replayed below is bounded by the
+	// number of orgs, and live updates are rare relative to polling.
+	sub := configs.NewSubscriber(len(d.cfgs) + 16)
+	var backlog []configs.ConfigUpdate
+	for orgID, view := range d.cfgs {
+		if view.ID > sinceID {
+			backlog = append(backlog, configs.ConfigUpdate{OrgID: orgID, ConfigView: view})
+		}
+	}
+	d.subs[sub] = struct{}{}
+	d.mu.Unlock()
+
+	for _, update := range backlog {
+		sub.Deliver(update)
+	}
+
+	go func() {
+		<-ctx.Done()
+		d.unsubscribe(sub)
+	}()
+
+	return sub.Chan(), nil
+}
+
+// unsubscribe removes sub from d.subs and closes it.
+func (d *DB) unsubscribe(sub *configs.Subscriber) {
+	d.mu.Lock()
+	delete(d.subs, sub)
+	d.mu.Unlock()
+	sub.Close()
+}
+
+// Close implements db.DB.
+func (d *DB) Close() error {
+	return nil
+}
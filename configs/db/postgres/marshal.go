@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/cortex/configs"
+)
+
+func marshalConfig(cfg configs.Config) ([]byte, error) {
+	return json.Marshal(cfg)
+}
+
+func unmarshalConfig(raw []byte, cfg *configs.Config) error {
+	return json.Unmarshal(raw, cfg)
+}
+
+// notifyPayload encodes the (orgID, configID) pair for a pg_notify payload.
+// Postgres notification payloads are capped at 8000 bytes, so we send just
+// enough for the subscriber to look up the fresh row rather than the config
+// itself.
+func notifyPayload(orgID configs.OrgID, id configs.ID) string {
+	return fmt.Sprintf("%s:%d", orgID, id)
+}
+
+func parseNotifyPayload(payload string) (configs.OrgID, configs.ID, error) {
+	parts := strings.SplitN(payload, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("malformed notify payload: %q", payload)
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed notify payload: %q", payload)
+	}
+	return configs.OrgID(parts[0]), configs.ID(id), nil
+}
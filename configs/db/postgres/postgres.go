@@ -0,0 +1,245 @@
+// Package postgres provides a Postgres-backed implementation of
+// configs/db.DB.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/weaveworks/cortex/configs"
+)
+
+const configUpdateChannel = "config_updates"
+
+// subscriberBufSize is the channel buffer given to each subscription. Unlike
+// memory.DB, the backlog size isn't known before a subscriber is registered
+// (see Subscribe), so this can't be sized off it the way memory.DB's can.
+const subscriberBufSize = 16
+
+// DB is a Postgres-backed implementation of db.DB.
+type DB struct {
+	db       *sql.DB
+	listener *pq.Listener
+
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+// subscription wraps a configs.Subscriber with deduplication by revision. A
+// subscriber is registered before its backlog is replayed (see Subscribe),
+// so any revision committed around the same time can otherwise be delivered
+// twice: once via dispatchNotifications and once via the backlog query. Each
+// delivered revision is tracked per org so the second, redundant delivery is
+// dropped instead of passed on.
+type subscription struct {
+	*configs.Subscriber
+
+	mu       sync.Mutex
+	lastSeen map[configs.OrgID]configs.ID
+}
+
+func newSubscription(bufSize int) *subscription {
+	return &subscription{
+		Subscriber: configs.NewSubscriber(bufSize),
+		lastSeen:   map[configs.OrgID]configs.ID{},
+	}
+}
+
+// deliver delivers update unless a revision at least as new has already been
+// delivered for its org.
+func (s *subscription) deliver(update configs.ConfigUpdate) {
+	s.mu.Lock()
+	if last, ok := s.lastSeen[update.OrgID]; ok && update.ID <= last {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSeen[update.OrgID] = update.ID
+	s.mu.Unlock()
+
+	s.Subscriber.Deliver(update)
+}
+
+// New creates a new Postgres DB.
+func New(uri string) (*DB, error) {
+	db, err := sql.Open("postgres", uri)
+	if err != nil {
+		return nil, err
+	}
+	listener := pq.NewListener(uri, 10*time.Second, time.Minute, nil)
+	if err := listener.Listen(configUpdateChannel); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	d := &DB{
+		db:       db,
+		listener: listener,
+		subs:     map[*subscription]struct{}{},
+	}
+	go d.dispatchNotifications()
+	return d, nil
+}
+
+// GetConfig implements db.DB.
+func (d *DB) GetConfig(ctx context.Context, orgID configs.OrgID) (configs.ConfigView, error) {
+	var view configs.ConfigView
+	var rawConfig []byte
+	err := d.db.QueryRowContext(ctx,
+		`SELECT id, config FROM configs WHERE org_id = $1 ORDER BY id DESC LIMIT 1`,
+		orgID,
+	).Scan(&view.ID, &rawConfig)
+	if err != nil {
+		return configs.ConfigView{}, err
+	}
+	if err := unmarshalConfig(rawConfig, &view.Config); err != nil {
+		return configs.ConfigView{}, err
+	}
+	return view, nil
+}
+
+// SetConfig implements db.DB. It persists the new revision and NOTIFYs
+// configUpdateChannel with the org ID and new config ID so that
+// dispatchNotifications can fan it out to every current subscriber without
+// polling.
+func (d *DB) SetConfig(ctx context.Context, orgID configs.OrgID, cfg configs.Config) error {
+	rawConfig, err := marshalConfig(cfg)
+	if err != nil {
+		return err
+	}
+	var id configs.ID
+	err = d.db.QueryRowContext(ctx,
+		`INSERT INTO configs (org_id, config) VALUES ($1, $2) RETURNING id`,
+		orgID, rawConfig,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, configUpdateChannel, notifyPayload(orgID, id))
+	return err
+}
+
+// GetAllConfigs implements db.DB.
+func (d *DB) GetAllConfigs(ctx context.Context) (map[configs.OrgID]configs.ConfigView, error) {
+	return d.getConfigsSince(ctx, 0)
+}
+
+// GetConfigs implements db.DB.
+func (d *DB) GetConfigs(ctx context.Context, since configs.ID) (map[configs.OrgID]configs.ConfigView, error) {
+	return d.getConfigsSince(ctx, since)
+}
+
+func (d *DB) getConfigsSince(ctx context.Context, since configs.ID) (map[configs.OrgID]configs.ConfigView, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (org_id) org_id, id, config
+		FROM configs
+		WHERE id > $1
+		ORDER BY org_id, id DESC
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[configs.OrgID]configs.ConfigView{}
+	for rows.Next() {
+		var orgID configs.OrgID
+		var view configs.ConfigView
+		var rawConfig []byte
+		if err := rows.Scan(&orgID, &view.ID, &rawConfig); err != nil {
+			return nil, err
+		}
+		if err := unmarshalConfig(rawConfig, &view.Config); err != nil {
+			return nil, err
+		}
+		result[orgID] = view
+	}
+	return result, rows.Err()
+}
+
+// Subscribe implements db.DB. It registers for updates fanned out by
+// dispatchNotifications *before* replaying persisted updates since sinceID,
+// so a revision that commits while the backlog query is still running is
+// seen via one path or the other rather than falling in the gap between
+// them and being silently missed; subscription.deliver drops whichever of
+// the two deliveries arrives second as a duplicate.
+func (d *DB) Subscribe(ctx context.Context, sinceID configs.ID) (<-chan configs.ConfigUpdate, error) {
+	sub := newSubscription(subscriberBufSize)
+
+	d.mu.Lock()
+	d.subs[sub] = struct{}{}
+	d.mu.Unlock()
+
+	backlog, err := d.getConfigsSince(ctx, sinceID)
+	if err != nil {
+		d.unsubscribe(sub)
+		return nil, err
+	}
+
+	for orgID, view := range backlog {
+		sub.deliver(configs.ConfigUpdate{OrgID: orgID, ConfigView: view})
+	}
+
+	go func() {
+		<-ctx.Done()
+		d.unsubscribe(sub)
+	}()
+
+	return sub.Chan(), nil
+}
+
+func (d *DB) unsubscribe(sub *subscription) {
+	d.mu.Lock()
+	delete(d.subs, sub)
+	d.mu.Unlock()
+	sub.Close()
+}
+
+// dispatchNotifications is the single reader of d.listener.Notify (a
+// *pq.Listener can only have one), and fans each NOTIFY out to every
+// currently-registered subscriber. Without this, with N concurrent watchers
+// a NOTIFY would only ever reach whichever one of their goroutines happened
+// to win the race to read it off the shared channel.
+func (d *DB) dispatchNotifications() {
+	ctx := context.Background()
+	for n := range d.listener.Notify {
+		if n == nil {
+			// pq.Listener sends a nil notification after it resubscribes
+			// following a dropped connection; there's nothing to dispatch.
+			continue
+		}
+
+		orgID, id, err := parseNotifyPayload(n.Extra)
+		if err != nil {
+			continue
+		}
+		view, err := d.GetConfig(ctx, orgID)
+		if err != nil || view.ID != id {
+			continue
+		}
+		d.dispatch(configs.ConfigUpdate{OrgID: orgID, ConfigView: view})
+	}
+}
+
+// dispatch delivers update to every currently-registered subscriber.
+func (d *DB) dispatch(update configs.ConfigUpdate) {
+	d.mu.Lock()
+	subs := make([]*subscription, 0, len(d.subs))
+	for sub := range d.subs {
+		subs = append(subs, sub)
+	}
+	d.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.deliver(update)
+	}
+}
+
+// Close implements db.DB.
+func (d *DB) Close() error {
+	d.listener.Close()
+	return d.db.Close()
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/weaveworks/cortex/configs"
+)
+
+// dispatch is what dispatchNotifications calls for every NOTIFY it reads off
+// the single shared pq.Listener; this locks in that it reaches every
+// subscriber currently registered via Subscribe, not just one of them. The
+// rest of Subscribe/dispatchNotifications needs a real Postgres connection
+// to exercise and isn't covered here.
+func TestDB_dispatch_FansOutToAllSubscribers(t *testing.T) {
+	d := &DB{subs: map[*subscription]struct{}{}}
+
+	sub1 := newSubscription(1)
+	sub2 := newSubscription(1)
+	d.subs[sub1] = struct{}{}
+	d.subs[sub2] = struct{}{}
+
+	update := configs.ConfigUpdate{OrgID: "org1", ConfigView: configs.ConfigView{ID: 1}}
+	d.dispatch(update)
+
+	for name, sub := range map[string]*subscription{"sub1": sub1, "sub2": sub2} {
+		select {
+		case got := <-sub.Chan():
+			require.Equal(t, update, got)
+		default:
+			t.Fatalf("%s did not receive the dispatched update", name)
+		}
+	}
+}
+
+// dispatch must not redeliver a revision a subscriber has already seen for
+// an org, since Subscribe can otherwise deliver the same revision once via
+// backlog replay and once via a concurrent dispatch.
+func TestDB_dispatch_DedupesAlreadyDeliveredRevisions(t *testing.T) {
+	d := &DB{subs: map[*subscription]struct{}{}}
+	sub := newSubscription(2)
+	d.subs[sub] = struct{}{}
+
+	update := configs.ConfigUpdate{OrgID: "org1", ConfigView: configs.ConfigView{ID: 1}}
+	sub.deliver(update) // simulates backlog replay delivering it first.
+	d.dispatch(update)  // simulates dispatchNotifications redelivering it.
+
+	require.Len(t, sub.Chan(), 1)
+}
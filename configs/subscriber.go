@@ -0,0 +1,55 @@
+package configs
+
+import "sync"
+
+// Subscriber is a single consumer of ConfigUpdates, shared by DB
+// implementations' Subscribe methods (see configs/db.DB). Updates are
+// delivered with a non-blocking send: a consumer that can't keep up with its
+// buffer is disconnected (its channel closed) rather than stalling whatever
+// goroutine is trying to notify it.
+type Subscriber struct {
+	mu     sync.Mutex
+	closed bool
+	ch     chan ConfigUpdate
+}
+
+// NewSubscriber creates a Subscriber whose channel has the given buffer size.
+func NewSubscriber(bufSize int) *Subscriber {
+	return &Subscriber{ch: make(chan ConfigUpdate, bufSize)}
+}
+
+// Chan returns the channel updates are delivered on. It is closed once the
+// subscriber is disconnected, either via Close or because it fell behind.
+func (s *Subscriber) Chan() <-chan ConfigUpdate {
+	return s.ch
+}
+
+// Deliver makes a non-blocking attempt to send update to the subscriber. If
+// its buffer is full, or it's already disconnected, it is (or remains)
+// closed rather than blocking the caller.
+func (s *Subscriber) Deliver(update ConfigUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- update:
+	default:
+		s.closeLocked()
+	}
+}
+
+// Close disconnects the subscriber, closing its channel if not already closed.
+func (s *Subscriber) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *Subscriber) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
+)
+
+// NoCompactReason describes why a block has been excluded from future
+// compactions.
+type NoCompactReason string
+
+const (
+	// ManualNoCompactReason is used when an operator has marked the block
+	// no-compact directly (e.g. via the compactor's admin tooling).
+	ManualNoCompactReason NoCompactReason = "manual"
+	// IndexOutOfOrderNoCompactReason is used when a block's index has been
+	// found to contain out-of-order chunks that would be unsafe to compact.
+	IndexOutOfOrderNoCompactReason NoCompactReason = "index-out-of-order"
+	// TooManySeriesNoCompactReason is used when compacting the block would
+	// produce a result exceeding configured series limits.
+	TooManySeriesNoCompactReason NoCompactReason = "too-many-series"
+	// OutOfRangeNoCompactReason is used when the planner itself finds a
+	// block whose time range doesn't fit any of the configured split/merge
+	// ranges.
+	OutOfRangeNoCompactReason NoCompactReason = "out-of-range"
+)
+
+// NoCompactMark is the marker persisted (bucket-backed, keyed by block ULID)
+// to record that a block must be excluded from compaction.
+type NoCompactMark struct {
+	ID     ulid.ULID       `json:"id"`
+	Reason NoCompactReason `json:"reason"`
+}
+
+// NoCompactMarksProvider loads the set of no-compact marks known at the time
+// of the call. The compactor refreshes this each compaction iteration, so
+// implementations don't need to cache beyond the lifetime of a single call.
+type NoCompactMarksProvider interface {
+	LoadNoCompactMarks(ctx context.Context) (map[ulid.ULID]*NoCompactMark, error)
+}
+
+// NoCompactMarker is a NoCompactMarksProvider that can also write new marks,
+// for when the planner itself decides a block must be excluded (such as an
+// out-of-range block).
+type NoCompactMarker interface {
+	NoCompactMarksProvider
+
+	// MarkNoCompact persists a no-compact mark for id with the given reason.
+	MarkNoCompact(ctx context.Context, id ulid.ULID, reason NoCompactReason) error
+}
+
+// SplitAndMergePlanner is a Thanos-compatible compactor.Planner. Its Plan
+// implementation filters out blocks marked no-compact (either by an
+// operator/other compactor component, or by Plan itself because the block
+// doesn't fit any of the configured ranges) and otherwise passes the
+// remaining blocks through unchanged.
+type SplitAndMergePlanner struct {
+	ranges []int64
+	marker NoCompactMarker
+	logger log.Logger
+
+	blocksMarkedForNoCompact *prometheus.CounterVec
+}
+
+// NewSplitAndMergePlanner creates a new SplitAndMergePlanner.
+func NewSplitAndMergePlanner(ranges []int64, marker NoCompactMarker, logger log.Logger, reg prometheus.Registerer) *SplitAndMergePlanner {
+	return &SplitAndMergePlanner{
+		ranges: ranges,
+		marker: marker,
+		logger: logger,
+		blocksMarkedForNoCompact: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_compactor_blocks_marked_for_no_compact_total",
+			Help: "Total number of blocks that have been marked for no-compact.",
+		}, []string{"reason"}),
+	}
+}
+
+// Plan implements compactor.Planner.
+func (p *SplitAndMergePlanner) Plan(ctx context.Context, blocks []*metadata.Meta) ([]*metadata.Meta, error) {
+	if len(blocks) == 0 {
+		return blocks, nil
+	}
+
+	marks, err := p.marker.LoadNoCompactMarks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load no-compact marks: %w", err)
+	}
+
+	largestRange := p.ranges[len(p.ranges)-1]
+	rangeStart, rangeEnd := rangeForTimestamp(blocks[0].MinTime, largestRange)
+
+	result := make([]*metadata.Meta, 0, len(blocks))
+	for _, b := range blocks {
+		if mark, ok := marks[b.ULID]; ok {
+			p.skipMarkedBlock(b, mark.Reason)
+			continue
+		}
+
+		if b.MinTime < rangeStart || b.MaxTime > rangeEnd {
+			if err := p.markOutOfRange(ctx, b, rangeStart, rangeEnd); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		result = append(result, b)
+	}
+
+	return result, nil
+}
+
+func (p *SplitAndMergePlanner) skipMarkedBlock(b *metadata.Meta, reason NoCompactReason) {
+	level.Info(p.logger).Log("msg", "skipping block marked for no-compact", "block", b.ULID.String(), "reason", reason)
+	p.blocksMarkedForNoCompact.WithLabelValues(string(reason)).Inc()
+}
+
+// markOutOfRange marks b as no-compact because it doesn't fit within the
+// largest configured range, rather than failing the whole compaction group.
+func (p *SplitAndMergePlanner) markOutOfRange(ctx context.Context, b *metadata.Meta, rangeStart, rangeEnd int64) error {
+	if err := p.marker.MarkNoCompact(ctx, b.ULID, OutOfRangeNoCompactReason); err != nil {
+		return fmt.Errorf("mark block %s out of range %d:%d as no-compact: %w", b.ULID.String(), rangeStart, rangeEnd, err)
+	}
+
+	level.Warn(p.logger).Log("msg", "marked block for no-compact because it's outside the largest expected range",
+		"block", b.ULID.String(), "block_range", fmt.Sprintf("%d:%d", b.MinTime, b.MaxTime), "expected_range", fmt.Sprintf("%d:%d", rangeStart, rangeEnd))
+	p.blocksMarkedForNoCompact.WithLabelValues(string(OutOfRangeNoCompactReason)).Inc()
+
+	return nil
+}
+
+// rangeForTimestamp returns the start/end of the range of the given width
+// that contains t, aligned to multiples of width.
+func rangeForTimestamp(t int64, width int64) (start, end int64) {
+	start = (t / width) * width
+	return start, start + width
+}
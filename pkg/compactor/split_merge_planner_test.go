@@ -4,39 +4,72 @@ package compactor
 
 import (
 	"context"
-	"fmt"
 	"testing"
 
+	"github.com/go-kit/log"
 	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/mimir/pkg/storage/tsdb/metadata"
 )
 
+// fakeNoCompactMarker is an in-memory NoCompactMarker used by the planner
+// tests in place of a bucket-backed implementation.
+type fakeNoCompactMarker struct {
+	marks map[ulid.ULID]*NoCompactMark
+}
+
+func newFakeNoCompactMarker(initial map[ulid.ULID]*NoCompactMark) *fakeNoCompactMarker {
+	if initial == nil {
+		initial = map[ulid.ULID]*NoCompactMark{}
+	}
+	return &fakeNoCompactMarker{marks: initial}
+}
+
+func (f *fakeNoCompactMarker) LoadNoCompactMarks(_ context.Context) (map[ulid.ULID]*NoCompactMark, error) {
+	return f.marks, nil
+}
+
+func (f *fakeNoCompactMarker) MarkNoCompact(_ context.Context, id ulid.ULID, reason NoCompactReason) error {
+	f.marks[id] = &NoCompactMark{ID: id, Reason: reason}
+	return nil
+}
+
 func TestSplitAndMergePlanner_Plan(t *testing.T) {
 	block1 := ulid.MustNew(1, nil)
 	block2 := ulid.MustNew(2, nil)
 	block3 := ulid.MustNew(3, nil)
 
 	tests := map[string]struct {
-		ranges          []int64
-		blocksByMinTime []*metadata.Meta
-		expectedErr     error
+		ranges           []int64
+		blocksByMinTime  []*metadata.Meta
+		existingMarks    map[ulid.ULID]*NoCompactMark
+		expectedBlocks   []*metadata.Meta
+		expectedNewMarks map[ulid.ULID]NoCompactReason
 	}{
 		"no blocks": {
 			ranges:          []int64{20, 40, 60},
 			blocksByMinTime: []*metadata.Meta{},
+			expectedBlocks:  []*metadata.Meta{},
 		},
-		"a source block is larger then the largest range": {
+		"a source block is larger then the largest range gets marked out-of-range and filtered": {
 			ranges: []int64{20, 40, 60},
 			blocksByMinTime: []*metadata.Meta{
 				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 0, MaxTime: 20, Version: metadata.TSDBVersion1}},
 				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 10, MaxTime: 80, Version: metadata.TSDBVersion1}},
 				{BlockMeta: tsdb.BlockMeta{ULID: block3, MinTime: 12, MaxTime: 15, Version: metadata.TSDBVersion1}},
 			},
-			expectedErr: fmt.Errorf("block %s with time range 10:80 is outside the largest expected range 0:60",
-				block2.String()),
+			expectedBlocks: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 0, MaxTime: 20, Version: metadata.TSDBVersion1}},
+				{BlockMeta: tsdb.BlockMeta{ULID: block3, MinTime: 12, MaxTime: 15, Version: metadata.TSDBVersion1}},
+			},
+			expectedNewMarks: map[ulid.ULID]NoCompactReason{
+				block2: OutOfRangeNoCompactReason,
+			},
 		},
 		"source blocks are smaller then the largest range but compacted block is larger": {
 			ranges: []int64{20, 40, 60},
@@ -45,17 +78,31 @@ func TestSplitAndMergePlanner_Plan(t *testing.T) {
 				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 30, MaxTime: 40, Version: metadata.TSDBVersion1}},
 				{BlockMeta: tsdb.BlockMeta{ULID: block3, MinTime: 50, MaxTime: 70, Version: metadata.TSDBVersion1}},
 			},
-			expectedErr: fmt.Errorf("block %s with time range 50:70 is outside the largest expected range 0:60",
-				block3.String()),
+			expectedBlocks: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 10, MaxTime: 20, Version: metadata.TSDBVersion1}},
+				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 30, MaxTime: 40, Version: metadata.TSDBVersion1}},
+			},
+			expectedNewMarks: map[ulid.ULID]NoCompactReason{
+				block3: OutOfRangeNoCompactReason,
+			},
 		},
 		"source blocks and compacted block are smaller then the largest range but misaligned": {
+			// rangeForTimestamp anchors the expected range off blocks[0].MinTime
+			// (50, not 0), aligned down to a multiple of the largest range (60),
+			// giving a window of 0 to 60. block1 fits that window, but block2 -
+			// despite being narrower than the largest range itself - lies
+			// entirely after it, so it's out of range.
 			ranges: []int64{20, 40, 60},
 			blocksByMinTime: []*metadata.Meta{
-				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 50, MaxTime: 70, Version: metadata.TSDBVersion1}},
-				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 70, MaxTime: 80, Version: metadata.TSDBVersion1}},
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 50, MaxTime: 60, Version: metadata.TSDBVersion1}},
+				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 65, MaxTime: 75, Version: metadata.TSDBVersion1}},
+			},
+			expectedBlocks: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 50, MaxTime: 60, Version: metadata.TSDBVersion1}},
+			},
+			expectedNewMarks: map[ulid.ULID]NoCompactReason{
+				block2: OutOfRangeNoCompactReason,
 			},
-			expectedErr: fmt.Errorf("block %s with time range 50:70 is outside the largest expected range 0:60",
-				block1.String()),
 		},
 		"blocks fit within the largest range": {
 			ranges: []int64{20, 40, 60},
@@ -64,18 +111,67 @@ func TestSplitAndMergePlanner_Plan(t *testing.T) {
 				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 20, MaxTime: 40, Version: metadata.TSDBVersion1}},
 				{BlockMeta: tsdb.BlockMeta{ULID: block3, MinTime: 20, MaxTime: 60, Version: metadata.TSDBVersion1}},
 			},
+			expectedBlocks: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 10, MaxTime: 20, Version: metadata.TSDBVersion1}},
+				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 20, MaxTime: 40, Version: metadata.TSDBVersion1}},
+				{BlockMeta: tsdb.BlockMeta{ULID: block3, MinTime: 20, MaxTime: 60, Version: metadata.TSDBVersion1}},
+			},
+		},
+		"a block already marked no-compact is filtered out without being re-marked": {
+			ranges: []int64{20, 40, 60},
+			blocksByMinTime: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 10, MaxTime: 20, Version: metadata.TSDBVersion1}},
+				{BlockMeta: tsdb.BlockMeta{ULID: block2, MinTime: 20, MaxTime: 40, Version: metadata.TSDBVersion1}},
+			},
+			existingMarks: map[ulid.ULID]*NoCompactMark{
+				block2: {ID: block2, Reason: TooManySeriesNoCompactReason},
+			},
+			expectedBlocks: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 10, MaxTime: 20, Version: metadata.TSDBVersion1}},
+			},
+		},
+		"a block with no mark and within range is included": {
+			ranges: []int64{20, 40, 60},
+			blocksByMinTime: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 10, MaxTime: 20, Version: metadata.TSDBVersion1}},
+			},
+			expectedBlocks: []*metadata.Meta{
+				{BlockMeta: tsdb.BlockMeta{ULID: block1, MinTime: 10, MaxTime: 20, Version: metadata.TSDBVersion1}},
+			},
 		},
 	}
 
 	for testName, testData := range tests {
 		t.Run(testName, func(t *testing.T) {
-			c := NewSplitAndMergePlanner(testData.ranges)
+			reg := prometheus.NewPedanticRegistry()
+			marker := newFakeNoCompactMarker(testData.existingMarks)
+			c := NewSplitAndMergePlanner(testData.ranges, marker, log.NewNopLogger(), reg)
+
 			actual, err := c.Plan(context.Background(), testData.blocksByMinTime)
-			assert.Equal(t, testData.expectedErr, err)
+			require.NoError(t, err)
+			assert.Equal(t, testData.expectedBlocks, actual)
 
-			if testData.expectedErr == nil {
-				// Since the planner is a pass-through we do expect to get the same input blocks on success.
-				assert.Equal(t, testData.blocksByMinTime, actual)
+			for id, reason := range testData.expectedNewMarks {
+				mark, ok := marker.marks[id]
+				if assert.True(t, ok, "expected block %s to be marked no-compact", id.String()) {
+					assert.Equal(t, reason, mark.Reason)
+				}
+			}
+
+			expectedTotal := float64(len(testData.expectedNewMarks))
+			for id := range testData.existingMarks {
+				found := false
+				for _, b := range testData.blocksByMinTime {
+					if b.ULID == id {
+						found = true
+					}
+				}
+				if found {
+					expectedTotal++
+				}
+			}
+			if expectedTotal > 0 {
+				assert.Equal(t, expectedTotal, testutil.ToFloat64(c.blocksMarkedForNoCompact))
 			}
 		})
 	}
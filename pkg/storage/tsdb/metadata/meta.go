@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package metadata holds the block meta.json representation used throughout
+// the compactor and store-gateway, extending Prometheus TSDB's own
+// tsdb.BlockMeta with Mimir/Thanos-specific fields.
+package metadata
+
+import (
+	"github.com/prometheus/prometheus/tsdb"
+)
+
+// Supported meta.json versions.
+const (
+	TSDBVersion1 = 1
+)
+
+// Meta describes a block's meta.json, as read from object storage.
+type Meta struct {
+	tsdb.BlockMeta
+
+	Thanos ThanosMeta `json:"thanos"`
+}
+
+// ThanosMeta holds the Thanos/Mimir-specific extensions to a block's
+// meta.json that aren't part of upstream Prometheus TSDB's BlockMeta.
+type ThanosMeta struct {
+	Labels map[string]string `json:"labels"`
+	Source string            `json:"source"`
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package functions
+
+import (
+	"fmt"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// errVectorLevelFunctionNotSupported is returned by the experimental
+// functions below, once enabled: unlike the rest of this file,
+// sort_by_label(_desc) and info() don't transform one series at a time, they
+// reorder or join across the whole instant vector for a step.
+// InstantVectorSeriesFunction only ever sees one series' data, so these can't
+// be implemented as one until the streaming engine grows a vector-level
+// function stage; until then, queries using them fall back to the
+// non-streaming engine, same as before this change.
+var errVectorLevelFunctionNotSupported = func(name string) error {
+	return fmt.Errorf("%s is not yet supported by the streaming PromQL engine", name)
+}
+
+// errExperimentalFunctionDisabled is returned in place of
+// errVectorLevelFunctionNotSupported when parser.EnableExperimentalFunctions
+// isn't set, so "disabled" and "not implemented yet" aren't indistinguishable
+// to a caller.
+var errExperimentalFunctionDisabled = func(name string) error {
+	return fmt.Errorf("%s is an experimental function that is not enabled", name)
+}
+
+// registerUnimplementedVectorLevelFunction registers name as an experimental
+// function the streaming engine can recognise but not yet evaluate (see
+// errVectorLevelFunctionNotSupported). It checks IsFunctionEnabled before
+// returning that error, so the Experimental metadata registered for name
+// actually gates something instead of being unread bookkeeping.
+func registerUnimplementedVectorLevelFunction(name string) InstantVectorSeriesFunction {
+	fn := func(_ types.InstantVectorSeriesData, _ []types.ScalarData, _ *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+		if !IsFunctionEnabled(name) {
+			return types.InstantVectorSeriesData{}, errExperimentalFunctionDisabled(name)
+		}
+		return types.InstantVectorSeriesData{}, errVectorLevelFunctionNotSupported(name)
+	}
+	return RegisterFunction(name, fn, FunctionMetadata{Experimental: true})
+}
+
+var SortByLabel = registerUnimplementedVectorLevelFunction("sort_by_label")
+var SortByLabelDesc = registerUnimplementedVectorLevelFunction("sort_by_label_desc")
+var Info = registerUnimplementedVectorLevelFunction("info")
@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package functions
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExperimentalFunctions_GatedByFeatureFlag(t *testing.T) {
+	require.False(t, IsFunctionEnabled("sort_by_label"))
+	require.False(t, IsFunctionEnabled("sort_by_label_desc"))
+	require.False(t, IsFunctionEnabled("info"))
+
+	// Non-experimental functions are unaffected by the flag.
+	require.True(t, IsFunctionEnabled("clamp_min"))
+}
+
+// The vector-level functions don't have a streaming implementation yet, but
+// they must still honour parser.EnableExperimentalFunctions themselves,
+// rather than only having it checked by their own test.
+func TestExperimentalFunctions_ErrorDependsOnFeatureFlag(t *testing.T) {
+	originalValue := parser.EnableExperimentalFunctions
+	t.Cleanup(func() { parser.EnableExperimentalFunctions = originalValue })
+
+	parser.EnableExperimentalFunctions = false
+	_, err := SortByLabel(histogramSeries(), nil, nil)
+	require.EqualError(t, err, "sort_by_label is an experimental function that is not enabled")
+
+	parser.EnableExperimentalFunctions = true
+	_, err = SortByLabel(histogramSeries(), nil, nil)
+	require.EqualError(t, err, "sort_by_label is not yet supported by the streaming PromQL engine")
+}
@@ -9,18 +9,21 @@ import (
 	"github.com/grafana/mimir/pkg/streamingpromql/types"
 )
 
-var Abs = FloatTransformationDropHistogramsFunc(math.Abs)
+// Abs, Ceil and Floor are no-ops on a native histogram (the histogram
+// already represents its own magnitude/roundedness), so they preserve
+// histogram samples untouched rather than dropping them.
+var Abs = RegisterFloatTransformation("abs", math.Abs, true)
 var Acos = FloatTransformationDropHistogramsFunc(math.Acos)
 var Acosh = FloatTransformationDropHistogramsFunc(math.Acosh)
 var Asin = FloatTransformationDropHistogramsFunc(math.Asin)
 var Asinh = FloatTransformationDropHistogramsFunc(math.Asinh)
 var Atan = FloatTransformationDropHistogramsFunc(math.Atan)
 var Atanh = FloatTransformationDropHistogramsFunc(math.Atanh)
-var Ceil = FloatTransformationDropHistogramsFunc(math.Ceil)
+var Ceil = RegisterFloatTransformation("ceil", math.Ceil, true)
 var Cos = FloatTransformationDropHistogramsFunc(math.Cos)
 var Cosh = FloatTransformationDropHistogramsFunc(math.Cosh)
 var Exp = FloatTransformationDropHistogramsFunc(math.Exp)
-var Floor = FloatTransformationDropHistogramsFunc(math.Floor)
+var Floor = RegisterFloatTransformation("floor", math.Floor, true)
 var Ln = FloatTransformationDropHistogramsFunc(math.Log)
 var Log10 = FloatTransformationDropHistogramsFunc(math.Log10)
 var Log2 = FloatTransformationDropHistogramsFunc(math.Log2)
@@ -38,7 +41,11 @@ var Rad = FloatTransformationDropHistogramsFunc(func(f float64) float64 {
 	return f * math.Pi / 180
 })
 
-var Sgn = FloatTransformationDropHistogramsFunc(func(f float64) float64 {
+// Sgn is not histogram-safe: a histogram has no single value to take the
+// sign of, so (unlike abs/ceil/floor) passing the sample through unchanged
+// would misrepresent it as a -1/0/1 result. Histograms are dropped, as
+// before.
+var Sgn = RegisterFloatTransformation("sgn", func(f float64) float64 {
 	if f < 0 {
 		return -1
 	}
@@ -50,9 +57,11 @@ var Sgn = FloatTransformationDropHistogramsFunc(func(f float64) float64 {
 	// This behaviour is undocumented, but if f is +/- NaN, Prometheus' engine returns that value.
 	// Otherwise, if the value is 0, we should return 0.
 	return f
-})
+}, false)
 
-var UnaryNegation InstantVectorSeriesFunction = func(seriesData types.InstantVectorSeriesData, _ []types.ScalarData, _ *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+// UnaryNegation is histogram-safe: negating a histogram is well-defined and
+// implemented via Histogram.Mul(-1) below.
+var UnaryNegation = RegisterFunction("unary_negation", func(seriesData types.InstantVectorSeriesData, _ []types.ScalarData, _ *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
 	for i := range seriesData.Floats {
 		seriesData.Floats[i].F = -seriesData.Floats[i].F
 	}
@@ -62,9 +71,11 @@ var UnaryNegation InstantVectorSeriesFunction = func(seriesData types.InstantVec
 	}
 
 	return seriesData, nil
-}
+}, FunctionMetadata{HistogramSafe: true})
 
-var Clamp InstantVectorSeriesFunction = func(seriesData types.InstantVectorSeriesData, scalarArgsData []types.ScalarData, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+// Clamp is not histogram-safe: clamping a histogram to a scalar range is
+// undefined, so histograms are dropped rather than passed through or clamped.
+var Clamp = RegisterFunction("clamp", func(seriesData types.InstantVectorSeriesData, scalarArgsData []types.ScalarData, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
 	outputIdx := 0
 	minArg := scalarArgsData[0]
 	maxArg := scalarArgsData[1]
@@ -85,4 +96,35 @@ var Clamp InstantVectorSeriesFunction = func(seriesData types.InstantVectorSerie
 	types.HPointSlicePool.Put(seriesData.Histograms, memoryConsumptionTracker)
 	seriesData.Histograms = nil
 	return seriesData, nil
-}
+}, FunctionMetadata{HistogramSafe: false})
+
+// ClampMin is not histogram-safe, like Clamp. Unlike Clamp, clamp_min never
+// drops a point: every input float has a defined output, so there's no
+// outputIdx compaction to do, only an in-place update of the reused FPoint
+// slice.
+var ClampMin = RegisterFunction("clamp_min", func(seriesData types.InstantVectorSeriesData, scalarArgsData []types.ScalarData, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+	minArg := scalarArgsData[0]
+	for step, data := range seriesData.Floats {
+		// max returns NaN if either argument is NaN, as Prometheus' clamp_min
+		// does: a NaN bound yields NaN even where the value itself is not NaN.
+		seriesData.Floats[step].F = max(data.F, minArg.Samples[step].F)
+	}
+
+	types.HPointSlicePool.Put(seriesData.Histograms, memoryConsumptionTracker)
+	seriesData.Histograms = nil
+	return seriesData, nil
+}, FunctionMetadata{HistogramSafe: false})
+
+// ClampMax is not histogram-safe, like Clamp.
+var ClampMax = RegisterFunction("clamp_max", func(seriesData types.InstantVectorSeriesData, scalarArgsData []types.ScalarData, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+	maxArg := scalarArgsData[0]
+	for step, data := range seriesData.Floats {
+		// min returns NaN if either argument is NaN, as Prometheus' clamp_max
+		// does: a NaN bound yields NaN even where the value itself is not NaN.
+		seriesData.Floats[step].F = min(data.F, maxArg.Samples[step].F)
+	}
+
+	types.HPointSlicePool.Put(seriesData.Histograms, memoryConsumptionTracker)
+	seriesData.Histograms = nil
+	return seriesData, nil
+}, FunctionMetadata{HistogramSafe: false})
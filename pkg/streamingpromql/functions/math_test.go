@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package functions
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+func histogramSeries() types.InstantVectorSeriesData {
+	return types.InstantVectorSeriesData{
+		Floats: []promql.FPoint{{T: 0, F: 1}},
+		Histograms: []promql.HPoint{
+			{T: 0, H: &histogram.FloatHistogram{Count: 10, Sum: 5}},
+		},
+	}
+}
+
+// UnaryNegation (e.g. `-some_metric`) is well-defined for native histograms,
+// so it must negate them in place rather than dropping them.
+func TestUnaryNegation_PreservesHistograms(t *testing.T) {
+	result, err := UnaryNegation(histogramSeries(), nil, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Histograms, 1)
+	require.Equal(t, -5.0, result.Histograms[0].H.Sum)
+
+	metadata, ok := FunctionMetadataFor("unary_negation")
+	require.True(t, ok)
+	require.True(t, metadata.HistogramSafe)
+}
+
+// clamp(v, min, max) has no well-defined meaning for a native histogram, so
+// histogram samples must continue to be dropped.
+func TestClamp_DropsHistograms(t *testing.T) {
+	scalarArgs := []types.ScalarData{
+		{Samples: []promql.FPoint{{T: 0, F: 0}}},
+		{Samples: []promql.FPoint{{T: 0, F: 10}}},
+	}
+
+	result, err := Clamp(histogramSeries(), scalarArgs, nil)
+	require.NoError(t, err)
+	require.Empty(t, result.Histograms)
+
+	metadata, ok := FunctionMetadataFor("clamp")
+	require.True(t, ok)
+	require.False(t, metadata.HistogramSafe)
+}
+
+// sgn has no single value to take the sign of for a histogram, so histogram
+// samples must continue to be dropped, not passed through unchanged.
+func TestSgn_DropsHistograms(t *testing.T) {
+	result, err := Sgn(histogramSeries(), nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, result.Histograms)
+
+	metadata, ok := FunctionMetadataFor("sgn")
+	require.True(t, ok)
+	require.False(t, metadata.HistogramSafe)
+}
+
+func TestClampMin(t *testing.T) {
+	testCases := map[string]struct {
+		floats   []promql.FPoint
+		min      []promql.FPoint
+		expected []promql.FPoint
+	}{
+		"min below all values: no-op": {
+			floats:   []promql.FPoint{{T: 0, F: 5}, {T: 1, F: 10}},
+			min:      []promql.FPoint{{T: 0, F: 0}, {T: 1, F: 0}},
+			expected: []promql.FPoint{{T: 0, F: 5}, {T: 1, F: 10}},
+		},
+		"min above all values: clamps up": {
+			floats:   []promql.FPoint{{T: 0, F: 5}, {T: 1, F: 10}},
+			min:      []promql.FPoint{{T: 0, F: 7}, {T: 1, F: 20}},
+			expected: []promql.FPoint{{T: 0, F: 7}, {T: 1, F: 20}},
+		},
+		"NaN bound propagates NaN, even though the value itself is not NaN": {
+			floats:   []promql.FPoint{{T: 0, F: 5}},
+			min:      []promql.FPoint{{T: 0, F: math.NaN()}},
+			expected: []promql.FPoint{{T: 0, F: math.NaN()}},
+		},
+		"empty input: no-op": {
+			floats:   []promql.FPoint{},
+			min:      []promql.FPoint{},
+			expected: []promql.FPoint{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			seriesData := types.InstantVectorSeriesData{Floats: tc.floats}
+			scalarArgs := []types.ScalarData{{Samples: tc.min}}
+
+			result, err := ClampMin(seriesData, scalarArgs, nil)
+			require.NoError(t, err)
+			requireFPointsEqual(t, tc.expected, result.Floats)
+		})
+	}
+}
+
+func TestClampMax(t *testing.T) {
+	testCases := map[string]struct {
+		floats   []promql.FPoint
+		max      []promql.FPoint
+		expected []promql.FPoint
+	}{
+		"max above all values: no-op": {
+			floats:   []promql.FPoint{{T: 0, F: 5}, {T: 1, F: 10}},
+			max:      []promql.FPoint{{T: 0, F: 100}, {T: 1, F: 100}},
+			expected: []promql.FPoint{{T: 0, F: 5}, {T: 1, F: 10}},
+		},
+		"max below all values: clamps down": {
+			floats:   []promql.FPoint{{T: 0, F: 5}, {T: 1, F: 10}},
+			max:      []promql.FPoint{{T: 0, F: 3}, {T: 1, F: 1}},
+			expected: []promql.FPoint{{T: 0, F: 3}, {T: 1, F: 1}},
+		},
+		"NaN bound propagates NaN, even though the value itself is not NaN": {
+			floats:   []promql.FPoint{{T: 0, F: 5}},
+			max:      []promql.FPoint{{T: 0, F: math.NaN()}},
+			expected: []promql.FPoint{{T: 0, F: math.NaN()}},
+		},
+		"empty input: no-op": {
+			floats:   []promql.FPoint{},
+			max:      []promql.FPoint{},
+			expected: []promql.FPoint{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			seriesData := types.InstantVectorSeriesData{Floats: tc.floats}
+			scalarArgs := []types.ScalarData{{Samples: tc.max}}
+
+			result, err := ClampMax(seriesData, scalarArgs, nil)
+			require.NoError(t, err)
+			requireFPointsEqual(t, tc.expected, result.Floats)
+		})
+	}
+}
+
+// requireFPointsEqual compares FPoints with NaN-aware float equality, since
+// require.Equal treats NaN != NaN.
+func requireFPointsEqual(t *testing.T, expected, actual []promql.FPoint) {
+	t.Helper()
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		require.Equal(t, expected[i].T, actual[i].T)
+		if math.IsNaN(expected[i].F) {
+			require.True(t, math.IsNaN(actual[i].F), "point %d: expected NaN, got %v", i, actual[i].F)
+			continue
+		}
+		require.Equal(t, expected[i].F, actual[i].F)
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package functions
+
+import "github.com/prometheus/prometheus/promql/parser"
+
+// FunctionMetadata describes properties of a registered PromQL function
+// implementation that callers outside this package need without having to
+// inspect (or re-implement) the function itself.
+type FunctionMetadata struct {
+	// HistogramSafe indicates that this function's InstantVectorSeriesFunction
+	// keeps native histogram samples in its output, either passing them
+	// through unchanged (abs, ceil, floor) or transforming them (unary
+	// negation). Functions that drop histogram samples, such as clamp and
+	// sgn, are registered with HistogramSafe false. HistogramSafe is recorded
+	// and readable via FunctionMetadataFor; no planner or pushdown code in
+	// this package reads it.
+	HistogramSafe bool
+
+	// Experimental indicates that this function is only available while
+	// parser.EnableExperimentalFunctions is set, matching the same feature
+	// flag upstream Prometheus uses to gate parsing of these functions.
+	Experimental bool
+}
+
+var functionMetadata = map[string]FunctionMetadata{}
+
+// RegisterFunction records metadata about fn under name and returns fn
+// unchanged, so that it can be used directly as an initialiser:
+//
+//	var Abs = RegisterFunction("abs", FloatTransformationPreserveHistogramsFunc(math.Abs), FunctionMetadata{HistogramSafe: true})
+func RegisterFunction(name string, fn InstantVectorSeriesFunction, metadata FunctionMetadata) InstantVectorSeriesFunction {
+	functionMetadata[name] = metadata
+	return fn
+}
+
+// FunctionMetadataFor returns the metadata registered for name via
+// RegisterFunction, and whether any was registered.
+//
+// Note: nothing outside this package consults HistogramSafe yet — the
+// planner-side pushdown decision it's meant to feed is follow-up work, not
+// part of this change.
+func FunctionMetadataFor(name string) (FunctionMetadata, bool) {
+	m, ok := functionMetadata[name]
+	return m, ok
+}
+
+// RegisterFloatTransformation registers a function that maps every float
+// sample through transform and does nothing else, deriving whether it
+// preserves or drops histogram samples directly from histogramSafe. Unlike
+// calling RegisterFunction with FloatTransformation{Preserve,Drop}HistogramsFunc
+// picked out by hand, this makes it impossible for the registered metadata
+// to disagree with the function's actual behaviour.
+func RegisterFloatTransformation(name string, transform func(float64) float64, histogramSafe bool) InstantVectorSeriesFunction {
+	var fn InstantVectorSeriesFunction
+	if histogramSafe {
+		fn = FloatTransformationPreserveHistogramsFunc(transform)
+	} else {
+		fn = FloatTransformationDropHistogramsFunc(transform)
+	}
+	return RegisterFunction(name, fn, FunctionMetadata{HistogramSafe: histogramSafe})
+}
+
+// IsFunctionEnabled returns whether name is currently usable: functions with
+// no registered metadata are assumed enabled, and functions registered as
+// Experimental require parser.EnableExperimentalFunctions to be set, the
+// same flag that gates parsing those functions at all.
+func IsFunctionEnabled(name string) bool {
+	metadata, ok := functionMetadata[name]
+	if !ok {
+		return true
+	}
+	return !metadata.Experimental || parser.EnableExperimentalFunctions
+}
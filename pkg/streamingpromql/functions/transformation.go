@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package functions
+
+import (
+	"github.com/grafana/mimir/pkg/streamingpromql/limiting"
+	"github.com/grafana/mimir/pkg/streamingpromql/types"
+)
+
+// FloatTransformationDropHistogramsFunc returns an InstantVectorSeriesFunction
+// that applies transform to every float sample and discards any native
+// histogram samples in the series, for functions whose result is undefined
+// (or not implemented) for histograms, such as the trigonometric functions.
+func FloatTransformationDropHistogramsFunc(transform func(float64) float64) InstantVectorSeriesFunction {
+	return func(seriesData types.InstantVectorSeriesData, _ []types.ScalarData, memoryConsumptionTracker *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+		for i := range seriesData.Floats {
+			seriesData.Floats[i].F = transform(seriesData.Floats[i].F)
+		}
+
+		types.HPointSlicePool.Put(seriesData.Histograms, memoryConsumptionTracker)
+		seriesData.Histograms = nil
+
+		return seriesData, nil
+	}
+}
+
+// FloatTransformationPreserveHistogramsFunc returns an
+// InstantVectorSeriesFunction that applies transform to every float sample
+// and leaves any native histogram samples untouched, for functions whose
+// result for a histogram is the histogram itself (e.g. abs, ceil, floor are
+// no-ops on a histogram). The histograms slice is passed through as-is: it
+// is neither copied nor returned to types.HPointSlicePool.
+func FloatTransformationPreserveHistogramsFunc(transform func(float64) float64) InstantVectorSeriesFunction {
+	return func(seriesData types.InstantVectorSeriesData, _ []types.ScalarData, _ *limiting.MemoryConsumptionTracker) (types.InstantVectorSeriesData, error) {
+		for i := range seriesData.Floats {
+			seriesData.Floats[i].F = transform(seriesData.Floats[i].F)
+		}
+
+		return seriesData, nil
+	}
+}